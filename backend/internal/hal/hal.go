@@ -0,0 +1,69 @@
+// Package hal builds HAL+JSON (application/hal+json) response bodies for
+// handlers that also want to keep serving plain application/json by
+// default. Clients opt in via the Accept header and get `_links`/`_embedded`
+// envelopes they can use to traverse related resources without hard-coding
+// URLs.
+package hal
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MediaType is the content type clients send in Accept to opt into HAL+JSON.
+const MediaType = "application/hal+json"
+
+// Wants reports whether c's Accept header explicitly opts into HAL+JSON.
+// This deliberately does not use c.Accepts(MediaType): Fiber's content
+// negotiation treats a missing Accept header or any "*/*" fallback (what
+// curl, axios and most non-browser JSON clients send by default) as
+// matching every offer, which would silently switch the default JSON
+// response over to HAL+JSON for nearly every existing caller. Requiring the
+// literal media type as a token in the raw header keeps plain JSON the
+// default unless a client asks for HAL+JSON by name.
+func Wants(c *fiber.Ctx) bool {
+	for _, part := range strings.Split(c.Get(fiber.HeaderAccept), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if mediaType == MediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// Link is a single HAL link relation.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Item flattens item's JSON fields and adds a `_links` object built from
+// links, producing a single HAL+JSON resource representation.
+func Item(item any, links fiber.Map) (fiber.Map, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	out := fiber.Map{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	out["_links"] = links
+	return out, nil
+}
+
+// Embed builds a collection-level HAL+JSON body:
+//
+//	{"_links":{"self":{"href":self}},"_embedded":{name:[items...]}}
+func Embed(self, name string, items []fiber.Map) fiber.Map {
+	return fiber.Map{
+		"_links": fiber.Map{
+			"self": Link{Href: self},
+		},
+		"_embedded": fiber.Map{
+			name: items,
+		},
+	}
+}
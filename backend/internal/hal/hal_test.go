@@ -0,0 +1,93 @@
+package hal
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type sampleItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestItemFlattensAndMergesLinks(t *testing.T) {
+	out, err := Item(sampleItem{ID: "1", Name: "Widget"}, fiber.Map{
+		"self": Link{Href: "/api/widgets/1"},
+	})
+	if err != nil {
+		t.Fatalf("Item: %v", err)
+	}
+
+	if out["id"] != "1" || out["name"] != "Widget" {
+		t.Errorf("Item did not flatten the source fields: %#v", out)
+	}
+
+	links, ok := out["_links"].(fiber.Map)
+	if !ok {
+		t.Fatalf("_links is %T, want fiber.Map", out["_links"])
+	}
+	if links["self"].(Link).Href != "/api/widgets/1" {
+		t.Errorf("_links.self.href = %v, want /api/widgets/1", links["self"])
+	}
+}
+
+func TestEmbedBuildsCollectionEnvelope(t *testing.T) {
+	items := []fiber.Map{{"id": "1"}, {"id": "2"}}
+
+	body := Embed("/api/widgets", "widgets", items)
+
+	links := body["_links"].(fiber.Map)
+	if links["self"].(Link).Href != "/api/widgets" {
+		t.Errorf("_links.self.href = %v, want /api/widgets", links["self"])
+	}
+
+	embedded := body["_embedded"].(fiber.Map)
+	gotItems, ok := embedded["widgets"].([]fiber.Map)
+	if !ok || len(gotItems) != 2 {
+		t.Errorf("_embedded.widgets = %#v, want the 2 items passed in", embedded["widgets"])
+	}
+}
+
+func TestWantsHonorsAcceptHeader(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{"explicit hal+json", "application/hal+json", true},
+		{"hal+json with params", "application/hal+json; charset=utf-8", true},
+		{"hal+json alongside other offers", "application/json, application/hal+json", true},
+		{"plain json", "application/json", false},
+		// curl, axios and most non-browser JSON clients send no Accept
+		// header, or a "*/*" fallback; c.Accepts() treats both as matching
+		// every offer, which would silently flip the default response over
+		// to HAL+JSON for nearly every existing caller.
+		{"absent header", "", false},
+		{"wildcard", "*/*", false},
+		{"wildcard among offers", "application/json, text/plain, */*", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			var got bool
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = Wants(c)
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Wants(Accept: %q) = %v, want %v", tc.accept, got, tc.want)
+			}
+		})
+	}
+}
@@ -2,18 +2,44 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"my-backend/internal/db"
+	"my-backend/internal/hal"
+	"my-backend/internal/middleware"
 	"my-backend/internal/models"
+	"my-backend/internal/query"
+	"my-backend/internal/realtime"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+func categoryLinks(category models.Categories) fiber.Map {
+	return fiber.Map{
+		"self":  hal.Link{Href: fmt.Sprintf("/api/categories/%s", category.CategoryID)},
+		"stock": hal.Link{Href: fmt.Sprintf("/api/warehouse/%s", category.StockID)},
+	}
+}
+
+var categorySortColumns = map[string]bool{
+	"CategoryName": true,
+}
+
+// categoryDoc decodes a categories document together with Mongo's own _id,
+// so the last page's _id can be turned into an opaque next_cursor.
+type categoryDoc struct {
+	models.Categories `bson:",inline"`
+	ID                primitive.ObjectID `bson:"_id"`
+}
+
 type categoryRequest struct {
 	StockID      string `json:"StockID"`
 	CategoryName string `json:"CategoryName"`
@@ -22,12 +48,18 @@ type categoryRequest struct {
 
 // ListCategories godoc
 // @Summary      List categories by stock
-// @Description  Returns categories filtered by StockID.
+// @Description  Returns a cursor-paginated, sorted and filtered page of a stock's categories. Rejects with 403 if the stock belongs to another user.
 // @Tags         categories
 // @Produce      json
-// @Param        stockId  query  string  true  "Stock ID (UUID)"
-// @Success      200  {array}   models.Categories
+// @Param        stockId      query  string  true   "Stock ID (UUID)"
+// @Param        limit        query  int     false  "Page size (default 20, max 200)"
+// @Param        cursor       query  string  false  "Opaque cursor from a previous page's next_cursor"
+// @Param        sort_column  query  string  false  "CategoryName"
+// @Param        sort_order   query  string  false  "asc or desc"
+// @Param        q            query  string  false  "Case-insensitive substring match on CategoryName"
+// @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/categories [get]
 func ListCategories(c *fiber.Ctx) error {
@@ -41,37 +73,92 @@ func ListCategories(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "stockId must be a valid UUID")
 	}
 
+	params, err := query.Parse(c, categorySortColumns, "CategoryName")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	stock, err := loadStock(ctx, stockUUID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "stock belongs to another user")
+	}
+
+	filter := bson.M{"StockID": stockUUID}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		filter["CategoryName"] = bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+	}
+
+	mongoFilter, opts := params.Apply(filter)
+
 	collection, err := db.CategoriesCollection(ctx)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
-	cursor, err := collection.Find(ctx, bson.M{"StockID": stockUUID})
+	cursor, err := collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to fetch categories")
 	}
 	defer cursor.Close(ctx)
 
-	var categories []models.Categories
-	if err := cursor.All(ctx, &categories); err != nil {
+	var docs []categoryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to decode categories")
 	}
 
-	return c.JSON(categories)
+	nextCursor := ""
+	if int64(len(docs)) > params.Limit {
+		docs = docs[:params.Limit]
+		last := docs[len(docs)-1]
+		nextCursor = query.EncodeCursor(query.SortValue(last.Categories, params.SortColumn), last.ID)
+	}
+
+	categories := make([]models.Categories, len(docs))
+	for i, doc := range docs {
+		categories[i] = doc.Categories
+	}
+
+	if hal.Wants(c) {
+		items := make([]fiber.Map, len(categories))
+		for i, category := range categories {
+			item, err := hal.Item(category, categoryLinks(category))
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+			}
+			items[i] = item
+		}
+		body := hal.Embed(c.OriginalURL(), "categories", items)
+		body["next_cursor"] = nextCursor
+		body["limit"] = params.Limit
+		return c.JSON(body)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        categories,
+		"next_cursor": nextCursor,
+		"limit":       params.Limit,
+	})
 }
 
 // CreateCategories godoc
 // @Summary      Bulk create categories
-// @Description  Creates multiple categories in a single request.
+// @Description  Creates multiple categories in a single request. Rejects with 403 if any referenced stock belongs to another user.
 // @Tags         categories
 // @Accept       json
 // @Produce      json
 // @Param        payload  body      []categoryRequest  true  "List of categories"
 // @Success      201  {array}   models.Categories
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/categories [post]
 func CreateCategories(c *fiber.Ctx) error {
@@ -87,6 +174,11 @@ func CreateCategories(c *fiber.Ctx) error {
 	categories := make([]models.Categories, len(payload))
 	docs := make([]interface{}, len(payload))
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stockOwners := make(map[uuid.UUID]uuid.UUID)
+
 	for i, cat := range payload {
 		cat.StockID = strings.TrimSpace(cat.StockID)
 		cat.CategoryName = strings.TrimSpace(cat.CategoryName)
@@ -101,6 +193,20 @@ func CreateCategories(c *fiber.Ctx) error {
 			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("StockID at index %d must be a valid UUID", i))
 		}
 
+		if _, ok := stockOwners[stockUUID]; !ok {
+			stock, err := loadStock(ctx, stockUUID)
+			if err != nil {
+				if errors.Is(err, mongo.ErrNoDocuments) {
+					return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("stock at index %d not found", i))
+				}
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+			}
+			if stock.UserID != middleware.UserID(c) {
+				return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("stock at index %d belongs to another user", i))
+			}
+			stockOwners[stockUUID] = stock.UserID
+		}
+
 		category := models.Categories{
 			CategoryID:   uuid.New(),
 			StockID:      stockUUID,
@@ -111,9 +217,6 @@ func CreateCategories(c *fiber.Ctx) error {
 		docs[i] = category
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	collection, err := db.CategoriesCollection(ctx)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
@@ -123,5 +226,72 @@ func CreateCategories(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to create categories")
 	}
 
+	source := c.Get("X-Request-Source")
+	for _, category := range categories {
+		realtime.Publish("category", "create", category, stockOwners[category.StockID], category.StockID, source)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(categories)
 }
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Deletes a category by ID. Rejects with 403 if the owning stock belongs to another user.
+// @Tags         categories
+// @Produce      json
+// @Param        categoryId  path  string  true  "Category ID (UUID)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /api/categories/{categoryId} [delete]
+func DeleteCategory(c *fiber.Ctx) error {
+	categoryIDParam := strings.TrimSpace(c.Params("categoryId"))
+	if categoryIDParam == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "categoryId is required")
+	}
+
+	categoryUUID, err := uuid.Parse(categoryIDParam)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "categoryId must be a valid UUID")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection, err := db.CategoriesCollection(ctx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
+	}
+
+	var existing models.Categories
+	err = collection.FindOne(ctx, bson.M{"CategoryID": categoryUUID}).Decode(&existing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.JSON(fiber.Map{"deleted_category": 0})
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete category")
+	}
+
+	stock, err := loadStock(ctx, existing.StockID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "category belongs to another user")
+	}
+
+	res, err := collection.DeleteOne(ctx, bson.M{"CategoryID": categoryUUID})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete category")
+	}
+
+	realtime.Publish("category", "delete", fiber.Map{"CategoryID": categoryUUID}, stock.UserID, existing.StockID, c.Get("X-Request-Source"))
+
+	return c.JSON(fiber.Map{
+		"deleted_category": res.DeletedCount,
+	})
+}
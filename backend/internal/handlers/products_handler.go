@@ -3,19 +3,54 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"my-backend/internal/db"
+	"my-backend/internal/hal"
+	"my-backend/internal/middleware"
 	"my-backend/internal/models"
+	"my-backend/internal/query"
+	"my-backend/internal/realtime"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var productSortColumns = map[string]bool{
+	"ProductName": true,
+	"ProductQty":  true,
+	"Category":    true,
+}
+
+// productDoc decodes a products document together with Mongo's own _id, so
+// the last page's _id can be turned into an opaque next_cursor.
+type productDoc struct {
+	models.Products `bson:",inline"`
+	ID              primitive.ObjectID `bson:"_id"`
+}
+
+func productLinks(product models.Products) fiber.Map {
+	links := fiber.Map{
+		"self":  hal.Link{Href: fmt.Sprintf("/api/products/%s", product.ProductID)},
+		"stock": hal.Link{Href: fmt.Sprintf("/api/warehouse/%s", product.StockID)},
+	}
+	if product.Category != "" {
+		// ListCategories has no exact-match filter, only stockId and the
+		// q substring search, so that's what this link points at.
+		links["category"] = hal.Link{Href: fmt.Sprintf("/api/categories?stockId=%s&q=%s", product.StockID, url.QueryEscape(product.Category))}
+	}
+	return links
+}
+
 type createProductRequest struct {
 	StockID     string `json:"StockID"`
 	ProductName string `json:"ProductName"`
@@ -33,12 +68,13 @@ type updateProductRequest struct {
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Deletes a product by ID.
+// @Description  Deletes a product by ID. Rejects with 403 if the owning stock belongs to another user.
 // @Tags         products
 // @Produce      json
 // @Param        productId  path  string  true  "Product ID (UUID)"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/products/{productId} [delete]
 func DeleteProduct(c *fiber.Ctx) error {
@@ -60,23 +96,58 @@ func DeleteProduct(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
-	res, err := collection.DeleteOne(ctx, bson.M{"ProductID": productUUID})
+	var existing models.Products
+	err = collection.FindOne(ctx, bson.M{"ProductID": productUUID}).Decode(&existing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.JSON(fiber.Map{"deleted_product": 0})
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete product")
+	}
+
+	stock, err := loadStock(ctx, existing.StockID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "product belongs to another user")
+	}
+
+	var deleted models.Products
+	err = collection.FindOneAndDelete(ctx, bson.M{"ProductID": productUUID}).Decode(&deleted)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.JSON(fiber.Map{"deleted_product": 0})
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete product")
 	}
 
+	realtime.Publish("product", "delete", deleted, stock.UserID, deleted.StockID, c.Get("X-Request-Source"))
+
 	return c.JSON(fiber.Map{
-		"deleted_product": res.DeletedCount,
+		"deleted_product": 1,
 	})
 }
 
 // ListProducts godoc
 // @Summary      List products
-// @Description  Returns products filtered by StockID.
+// @Description  Returns a cursor-paginated, sorted and filtered page of products for a stock. Rejects with 403 if the stock belongs to another user.
 // @Tags         products
 // @Produce      json
-// @Param        stockId  query  string  true  "Stock ID (UUID)"
-// @Success      200  {array}   models.Products
+// @Param        stockId      query  string  true   "Stock ID (UUID)"
+// @Param        limit        query  int     false  "Page size (default 20, max 200)"
+// @Param        cursor       query  string  false  "Opaque cursor from a previous page's next_cursor"
+// @Param        sort_column  query  string  false  "ProductName, ProductQty or Category"
+// @Param        sort_order   query  string  false  "asc or desc"
+// @Param        category     query  string  false  "Exact Category match"
+// @Param        minQty       query  int     false  "Minimum ProductQty"
+// @Param        q            query  string  false  "Case-insensitive substring match on ProductName"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/products [get]
 func ListProducts(c *fiber.Ctx) error {
@@ -90,37 +161,102 @@ func ListProducts(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "stockId must be a valid UUID")
 	}
 
+	params, err := query.Parse(c, productSortColumns, "ProductName")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	stock, err := loadStock(ctx, stockUUID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "stock belongs to another user")
+	}
+
+	filter := bson.M{"StockID": stockUUID}
+	if category := strings.TrimSpace(c.Query("category")); category != "" {
+		filter["Category"] = category
+	}
+	if raw := strings.TrimSpace(c.Query("minQty")); raw != "" {
+		minQty, err := strconv.Atoi(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "minQty must be an integer")
+		}
+		filter["ProductQty"] = bson.M{"$gte": minQty}
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		filter["ProductName"] = bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+	}
+
+	mongoFilter, opts := params.Apply(filter)
+
 	collection, err := db.ProductsCollection(ctx)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
-	cursor, err := collection.Find(ctx, bson.M{"StockID": stockUUID})
+	cursor, err := collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to fetch products")
 	}
 	defer cursor.Close(ctx)
 
-	var products []models.Products
-	if err := cursor.All(ctx, &products); err != nil {
+	var docs []productDoc
+	if err := cursor.All(ctx, &docs); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to decode products")
 	}
 
-	return c.JSON(products)
+	nextCursor := ""
+	if int64(len(docs)) > params.Limit {
+		docs = docs[:params.Limit]
+		last := docs[len(docs)-1]
+		nextCursor = query.EncodeCursor(query.SortValue(last.Products, params.SortColumn), last.ID)
+	}
+
+	products := make([]models.Products, len(docs))
+	for i, doc := range docs {
+		products[i] = doc.Products
+	}
+
+	if hal.Wants(c) {
+		items := make([]fiber.Map, len(products))
+		for i, product := range products {
+			item, err := hal.Item(product, productLinks(product))
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+			}
+			items[i] = item
+		}
+		body := hal.Embed(c.OriginalURL(), "products", items)
+		body["next_cursor"] = nextCursor
+		body["limit"] = params.Limit
+		return c.JSON(body)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        products,
+		"next_cursor": nextCursor,
+		"limit":       params.Limit,
+	})
 }
 
 // CreateProduct godoc
 // @Summary      Create a product
-// @Description  Creates a new product record.
+// @Description  Creates a new product record. Rejects with 403 if the owning stock belongs to another user.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        payload  body      createProductRequest  true  "Product data"
 // @Success      201  {object}  models.Products
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/products [post]
 func CreateProduct(c *fiber.Ctx) error {
@@ -149,6 +285,17 @@ func CreateProduct(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	stock, err := loadStock(ctx, stockUUID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "stock belongs to another user")
+	}
+
 	collection, err := db.ProductsCollection(ctx)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
@@ -167,12 +314,22 @@ func CreateProduct(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to create product")
 	}
 
+	realtime.Publish("product", "create", product, stock.UserID, product.StockID, c.Get("X-Request-Source"))
+
+	if hal.Wants(c) {
+		item, err := hal.Item(product, productLinks(product))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+		}
+		return c.Status(fiber.StatusCreated).JSON(item)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(product)
 }
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Updates mutable fields on an existing product.
+// @Description  Updates mutable fields on an existing product. Rejects with 403 if the owning stock belongs to another user.
 // @Tags         products
 // @Accept       json
 // @Produce      json
@@ -180,6 +337,7 @@ func CreateProduct(c *fiber.Ctx) error {
 // @Param        payload    body      updateProductRequest   true  "Fields to update"
 // @Success      200        {object}  models.Products
 // @Failure      400        {object}  map[string]string
+// @Failure      403        {object}  map[string]string
 // @Failure      404        {object}  map[string]string
 // @Failure      500        {object}  map[string]string
 // @Router       /api/products/{productId} [put]
@@ -237,6 +395,26 @@ func UpdateProduct(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
+	var existing models.Products
+	err = collection.FindOne(ctx, bson.M{"ProductID": productUUID}).Decode(&existing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "product not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update product")
+	}
+
+	stock, err := loadStock(ctx, existing.StockID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "product belongs to another user")
+	}
+
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	res := collection.FindOneAndUpdate(ctx, bson.M{"ProductID": productUUID}, bson.M{"$set": updates}, opts)
 	var updated models.Products
@@ -250,5 +428,15 @@ func UpdateProduct(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to decode updated product")
 	}
 
+	realtime.Publish("product", "update", updated, stock.UserID, updated.StockID, c.Get("X-Request-Source"))
+
+	if hal.Wants(c) {
+		item, err := hal.Item(updated, productLinks(updated))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+		}
+		return c.JSON(item)
+	}
+
 	return c.JSON(updated)
 }
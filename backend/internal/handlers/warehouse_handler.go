@@ -2,30 +2,117 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"my-backend/internal/db"
+	"my-backend/internal/hal"
+	"my-backend/internal/middleware"
 	"my-backend/internal/models"
+	"my-backend/internal/query"
+	"my-backend/internal/realtime"
+	"my-backend/internal/seeds"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// loadStock fetches a stock by ID. Mutation handlers on its child products
+// and categories use it to check UserID ownership before acting.
+func loadStock(ctx context.Context, stockID uuid.UUID) (models.Warehouse, error) {
+	collection, err := db.WarehouseCollection(ctx)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	var stock models.Warehouse
+	err = collection.FindOne(ctx, bson.M{"StockID": stockID}).Decode(&stock)
+	return stock, err
+}
+
+func stockLinks(stockID uuid.UUID) fiber.Map {
+	return fiber.Map{
+		"self":       hal.Link{Href: fmt.Sprintf("/api/warehouse/%s", stockID)},
+		"products":   hal.Link{Href: fmt.Sprintf("/api/products?stockId=%s", stockID)},
+		"categories": hal.Link{Href: fmt.Sprintf("/api/categories?stockId=%s", stockID)},
+	}
+}
+
+var warehouseSortColumns = map[string]bool{
+	"StockName": true,
+}
+
+// warehouseDoc decodes a warehouse document together with Mongo's own _id,
+// so the last page's _id can be turned into an opaque next_cursor.
+type warehouseDoc struct {
+	models.Warehouse `bson:",inline"`
+	ID               primitive.ObjectID `bson:"_id"`
+}
+
 type createStockRequest struct {
-	UserID    string `json:"UserID"`
 	StockName string `json:"StockName"`
 }
 
+// stockDeletionPlan is DeleteStock's response to the cascade/dryRun flags
+// once product/category counts are known, computed before any mutation
+// happens. proceed is false when plan.status/plan.body is the final
+// response; proceed is true when the caller should go on to perform the
+// actual transactional delete.
+type stockDeletionPlan struct {
+	status  int
+	body    fiber.Map
+	proceed bool
+}
+
+// planStockDeletion decides whether DeleteStock should refuse (cascade=false
+// with existing products), report a dry run, or proceed to the real delete.
+// Split out from DeleteStock so these branches can be unit tested without a
+// live Mongo connection.
+func planStockDeletion(dryRun, cascade bool, productCount, categoryCount int64) stockDeletionPlan {
+	if !cascade && productCount > 0 {
+		return stockDeletionPlan{
+			status: fiber.StatusConflict,
+			body: fiber.Map{
+				"error":              "stock has child products; retry with cascade=true",
+				"deleted_stock":      0,
+				"deleted_products":   0,
+				"deleted_categories": 0,
+			},
+		}
+	}
+
+	if dryRun {
+		return stockDeletionPlan{
+			status: fiber.StatusOK,
+			body: fiber.Map{
+				"dry_run":            true,
+				"deleted_stock":      1,
+				"deleted_products":   productCount,
+				"deleted_categories": categoryCount,
+			},
+		}
+	}
+
+	return stockDeletionPlan{proceed: true}
+}
+
 // DeleteStock godoc
 // @Summary      Delete a stock
-// @Description  Deletes a stock by ID and removes related products with the same StockID.
+// @Description  Atomically deletes a stock together with its products and categories. ?dryRun=true reports counts without mutating data; ?cascade=false (default true) refuses with 409 when child products exist.
 // @Tags         warehouse
 // @Produce      json
-// @Param        stockId  path  string  true  "Stock ID (UUID)"
+// @Param        stockId  path   string  true   "Stock ID (UUID)"
+// @Param        dryRun   query  bool    false  "Report what would be deleted without deleting"
+// @Param        cascade  query  bool    false  "Set to false to refuse deletion when products exist"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]string
 // @Router       /api/warehouse/{stockId} [delete]
 func DeleteStock(c *fiber.Ctx) error {
@@ -39,6 +126,9 @@ func DeleteStock(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "stockId must be a valid UUID")
 	}
 
+	dryRun := c.QueryBool("dryRun", false)
+	cascade := c.QueryBool("cascade", true)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -50,43 +140,117 @@ func DeleteStock(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
+	categoriesCol, err := db.CategoriesCollection(ctx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
+	}
+
+	var stock models.Warehouse
+	err = warehouseCol.FindOne(ctx, bson.M{"StockID": stockUUID}).Decode(&stock)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.NewError(fiber.StatusNotFound, "stock not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load stock")
+	}
+	if stock.UserID != middleware.UserID(c) {
+		return fiber.NewError(fiber.StatusForbidden, "stock belongs to another user")
+	}
 
-	stockRes, err := warehouseCol.DeleteOne(ctx, bson.M{"StockID": stockUUID})
+	productCount, err := productsCol.CountDocuments(ctx, bson.M{"StockID": stockUUID})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to count related products")
+	}
+	categoryCount, err := categoriesCol.CountDocuments(ctx, bson.M{"StockID": stockUUID})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete stock")
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to count related categories")
+	}
+
+	if plan := planStockDeletion(dryRun, cascade, productCount, categoryCount); !plan.proceed {
+		return c.Status(plan.status).JSON(plan.body)
 	}
 
-	productRes, err := productsCol.DeleteMany(ctx, bson.M{"StockID": stockUUID})
+	client, err := db.Client(ctx)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete related products")
+		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
+	session, err := client.StartSession()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to start transaction")
+	}
+	defer session.EndSession(ctx)
+
+	var deletedStock, deletedProducts, deletedCategories int64
+	abortedAt := ""
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		stockRes, err := warehouseCol.DeleteOne(sessCtx, bson.M{"StockID": stockUUID})
+		if err != nil {
+			abortedAt = "stock"
+			return nil, err
+		}
+		deletedStock = stockRes.DeletedCount
+
+		productRes, err := productsCol.DeleteMany(sessCtx, bson.M{"StockID": stockUUID})
+		if err != nil {
+			abortedAt = "products"
+			return nil, err
+		}
+		deletedProducts = productRes.DeletedCount
+
+		categoryRes, err := categoriesCol.DeleteMany(sessCtx, bson.M{"StockID": stockUUID})
+		if err != nil {
+			abortedAt = "categories"
+			return nil, err
+		}
+		deletedCategories = categoryRes.DeletedCount
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to delete stock: aborted at %s step", abortedAt))
+	}
+
+	realtime.Publish("stock", "delete", fiber.Map{"StockID": stockUUID}, stock.UserID, stockUUID, c.Get("X-Request-Source"))
+
 	return c.JSON(fiber.Map{
-		"deleted_stock":           stockRes.DeletedCount,
-		"deleted_relatedProducts": productRes.DeletedCount,
+		"deleted_stock":      deletedStock,
+		"deleted_products":   deletedProducts,
+		"deleted_categories": deletedCategories,
 	})
 }
 
 // ListWarehouse godoc
 // @Summary      List warehouse
-// @Description  Returns warehouse filtered by UserID.
+// @Description  Returns a cursor-paginated, sorted and filtered page of the authenticated user's stocks.
 // @Tags         warehouse
 // @Produce      json
-// @Param        userId  query  string  true  "User ID (UUID)"
-// @Success      200  {array}   models.Warehouse
+// @Param        limit        query  int     false  "Page size (default 20, max 200)"
+// @Param        cursor       query  string  false  "Opaque cursor from a previous page's next_cursor"
+// @Param        sort_column  query  string  false  "StockName"
+// @Param        sort_order   query  string  false  "asc or desc"
+// @Param        q            query  string  false  "Case-insensitive substring match on StockName"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/warehouse [get]
 func ListWarehouse(c *fiber.Ctx) error {
-	userIDParam := strings.TrimSpace(c.Query("userId"))
-	if userIDParam == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "userId is required")
-	}
+	userUUID := middleware.UserID(c)
 
-	userUUID, err := uuid.Parse(userIDParam)
+	params, err := query.Parse(c, warehouseSortColumns, "StockName")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "userId must be a valid UUID")
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	filter := bson.M{"UserID": userUUID}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		filter["StockName"] = bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+	}
+
+	mongoFilter, opts := params.Apply(filter)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -95,23 +259,54 @@ func ListWarehouse(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "database unavailable")
 	}
 
-	cursor, err := collection.Find(ctx, bson.M{"UserID": userUUID})
+	cursor, err := collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to fetch warehouse")
 	}
 	defer cursor.Close(ctx)
 
-	var warehouse []models.Warehouse
-	if err := cursor.All(ctx, &warehouse); err != nil {
+	var docs []warehouseDoc
+	if err := cursor.All(ctx, &docs); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to decode warehouse")
 	}
 
-	return c.JSON(warehouse)
+	nextCursor := ""
+	if int64(len(docs)) > params.Limit {
+		docs = docs[:params.Limit]
+		last := docs[len(docs)-1]
+		nextCursor = query.EncodeCursor(query.SortValue(last.Warehouse, params.SortColumn), last.ID)
+	}
+
+	warehouse := make([]models.Warehouse, len(docs))
+	for i, doc := range docs {
+		warehouse[i] = doc.Warehouse
+	}
+
+	if hal.Wants(c) {
+		items := make([]fiber.Map, len(warehouse))
+		for i, stock := range warehouse {
+			item, err := hal.Item(stock, stockLinks(stock.StockID))
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+			}
+			items[i] = item
+		}
+		body := hal.Embed(c.OriginalURL(), "warehouse", items)
+		body["next_cursor"] = nextCursor
+		body["limit"] = params.Limit
+		return c.JSON(body)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        warehouse,
+		"next_cursor": nextCursor,
+		"limit":       params.Limit,
+	})
 }
 
 // CreateStock godoc
 // @Summary      Create a stock
-// @Description  Creates a new stock record.
+// @Description  Creates a new stock record owned by the authenticated user.
 // @Tags         warehouse
 // @Accept       json
 // @Produce      json
@@ -127,16 +322,12 @@ func CreateStock(c *fiber.Ctx) error {
 	}
 
 	req.StockName = strings.TrimSpace(req.StockName)
-	req.UserID = strings.TrimSpace(req.UserID)
 
-	if req.StockName == "" || req.UserID == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "UserID and StockName are required")
+	if req.StockName == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "StockName is required")
 	}
 
-	userUUID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "UserID must be a valid UUID")
-	}
+	userUUID := middleware.UserID(c)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -156,5 +347,71 @@ func CreateStock(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to create stock")
 	}
 
+	realtime.Publish("stock", "create", stock, stock.UserID, stock.StockID, c.Get("X-Request-Source"))
+
+	if hal.Wants(c) {
+		item, err := hal.Item(stock, stockLinks(stock.StockID))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to build HAL response")
+		}
+		return c.Status(fiber.StatusCreated).JSON(item)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(stock)
 }
+
+// ImportWarehouse godoc
+// @Summary      Bulk import a warehouse
+// @Description  Creates stocks, categories and products owned by the authenticated user from a nested JSON document. ?upsert=true matches existing stocks/categories by name and merges their children instead of duplicating them.
+// @Tags         warehouse
+// @Accept       json
+// @Produce      json
+// @Param        upsert   query  bool             false  "Merge into existing stocks/categories matched by name"
+// @Param        payload  body   seeds.Warehouse  true   "Nested stocks/categories/products to import"
+// @Success      201  {object}  seeds.Result
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /api/warehouse/import [post]
+func ImportWarehouse(c *fiber.Ctx) error {
+	userUUID := middleware.UserID(c)
+
+	var doc seeds.Warehouse
+	if err := c.BodyParser(&doc); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid JSON payload")
+	}
+	if len(doc.Stocks) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "at least one stock is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := seeds.Import(ctx, userUUID, doc, c.QueryBool("upsert", false))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to import warehouse")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// ExportWarehouse godoc
+// @Summary      Export a warehouse
+// @Description  Returns the authenticated user's entire warehouse as the nested JSON shape accepted by import, for backup and restore.
+// @Tags         warehouse
+// @Produce      json
+// @Success      200  {object}  seeds.Warehouse
+// @Failure      500  {object}  map[string]string
+// @Router       /api/warehouse/export [get]
+func ExportWarehouse(c *fiber.Ctx) error {
+	userUUID := middleware.UserID(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	doc, err := seeds.Export(ctx, userUUID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to export warehouse")
+	}
+
+	return c.JSON(doc)
+}
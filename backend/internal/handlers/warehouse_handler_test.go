@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPlanStockDeletionRefusesCascadeWhenProductsExist(t *testing.T) {
+	plan := planStockDeletion(false, false, 3, 1)
+
+	if plan.proceed {
+		t.Fatal("plan.proceed = true, want false when cascade=false and products exist")
+	}
+	if plan.status != fiber.StatusConflict {
+		t.Errorf("plan.status = %d, want %d", plan.status, fiber.StatusConflict)
+	}
+	if plan.body["deleted_stock"] != 0 || plan.body["deleted_products"] != 0 || plan.body["deleted_categories"] != 0 {
+		t.Errorf("a refused deletion should report zero deletions, got %#v", plan.body)
+	}
+}
+
+func TestPlanStockDeletionAllowsNonCascadeWithNoProducts(t *testing.T) {
+	plan := planStockDeletion(false, false, 0, 2)
+
+	if !plan.proceed {
+		t.Error("plan.proceed = false, want true when cascade=false but there are no products to block it")
+	}
+}
+
+func TestPlanStockDeletionReportsDryRunWithoutMutating(t *testing.T) {
+	plan := planStockDeletion(true, true, 5, 2)
+
+	if plan.proceed {
+		t.Fatal("plan.proceed = true, want false for a dry run")
+	}
+	if plan.status != fiber.StatusOK {
+		t.Errorf("plan.status = %d, want %d", plan.status, fiber.StatusOK)
+	}
+	if plan.body["dry_run"] != true {
+		t.Errorf("plan.body[dry_run] = %v, want true", plan.body["dry_run"])
+	}
+	if plan.body["deleted_stock"] != 1 || plan.body["deleted_products"] != int64(5) || plan.body["deleted_categories"] != int64(2) {
+		t.Errorf("dry run should echo back the observed counts, got %#v", plan.body)
+	}
+}
+
+func TestPlanStockDeletionCascadeRefusalTakesPriorityOverDryRun(t *testing.T) {
+	// A cascade=false request that would be refused is refused outright,
+	// even when dryRun is also set - the caller asked to be blocked, not
+	// merely informed.
+	plan := planStockDeletion(true, false, 3, 0)
+
+	if plan.proceed {
+		t.Fatal("plan.proceed = true, want false")
+	}
+	if plan.status != fiber.StatusConflict {
+		t.Errorf("expected the cascade-refusal branch to win, got status %d body %#v", plan.status, plan.body)
+	}
+}
+
+func TestPlanStockDeletionProceedsWhenNothingBlocksIt(t *testing.T) {
+	plan := planStockDeletion(false, true, 4, 1)
+
+	if !plan.proceed {
+		t.Error("plan.proceed = false, want true when neither cascade refusal nor dry run applies")
+	}
+	if plan.body != nil {
+		t.Errorf("a proceeding plan should carry no response body, got %#v", plan.body)
+	}
+}
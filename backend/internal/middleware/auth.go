@@ -0,0 +1,73 @@
+// Package middleware provides Fiber middleware shared across route groups.
+package middleware
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// claims is the payload LoginUser signs into a token's body.
+type claims struct {
+	UserID string `json:"userID"`
+	jwt.RegisteredClaims
+}
+
+// ParseToken verifies a JWT string signed the way LoginUser issues tokens and
+// returns the user UUID embedded in it. Auth reads the token from the
+// Authorization header; the /api/ws upgrade check reads it from a query
+// parameter instead, since browsers cannot set custom headers on a WebSocket
+// handshake.
+func ParseToken(tokenString string) (uuid.UUID, error) {
+	if tokenString == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "missing bearer token")
+	}
+
+	var parsed claims
+	token, err := jwt.ParseWithClaims(tokenString, &parsed, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fiber.ErrUnauthorized
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "invalid or expired token")
+	}
+
+	userID, err := uuid.Parse(parsed.UserID)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "token has an invalid user id")
+	}
+
+	return userID, nil
+}
+
+// Auth parses a Bearer JWT issued by LoginUser, verifies its signature and
+// expiry, and stores the authenticated user's UUID in c.Locals("userID") for
+// downstream handlers. Requests without a valid token are rejected with 401
+// before reaching the route handler.
+func Auth(c *fiber.Ctx) error {
+	header := c.Get("Authorization")
+	tokenString := strings.TrimPrefix(header, "Bearer ")
+	if tokenString == "" || tokenString == header {
+		return fiber.NewError(fiber.StatusUnauthorized, "missing bearer token")
+	}
+
+	userID, err := ParseToken(tokenString)
+	if err != nil {
+		return err
+	}
+
+	c.Locals("userID", userID)
+	return c.Next()
+}
+
+// UserID reads the authenticated user's UUID stashed by Auth. It must only
+// be called on routes Auth protects.
+func UserID(c *fiber.Ctx) uuid.UUID {
+	userID, _ := c.Locals("userID").(uuid.UUID)
+	return userID
+}
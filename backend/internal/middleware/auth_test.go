@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func signToken(t *testing.T, secret string, userID string, expiry time.Duration) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+		},
+	})
+
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func TestParseTokenValid(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	userID := uuid.New()
+	tokenString := signToken(t, "test-secret", userID.String(), time.Hour)
+
+	got, err := ParseToken(tokenString)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if got != userID {
+		t.Errorf("ParseToken userID = %v, want %v", got, userID)
+	}
+}
+
+func TestParseTokenRejectsWrongSignature(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	tokenString := signToken(t, "wrong-secret", uuid.New().String(), time.Hour)
+
+	if _, err := ParseToken(tokenString); err == nil {
+		t.Fatal("ParseToken accepted a token signed with the wrong secret")
+	}
+}
+
+func TestParseTokenRejectsExpired(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	tokenString := signToken(t, "test-secret", uuid.New().String(), -time.Hour)
+
+	if _, err := ParseToken(tokenString); err == nil {
+		t.Fatal("ParseToken accepted an expired token")
+	}
+}
+
+func TestParseTokenRejectsInvalidUserID(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	tokenString := signToken(t, "test-secret", "not-a-uuid", time.Hour)
+
+	if _, err := ParseToken(tokenString); err == nil {
+		t.Fatal("ParseToken accepted a token with a malformed user id")
+	}
+}
+
+func TestParseTokenRejectsEmpty(t *testing.T) {
+	if _, err := ParseToken(""); err == nil {
+		t.Fatal("ParseToken accepted an empty token string")
+	}
+}
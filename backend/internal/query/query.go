@@ -0,0 +1,163 @@
+// Package query parses the limit/cursor/sort parameters shared by the list
+// handlers and turns them into a Mongo options.Find plus a filter that can
+// be merged with each handler's own field filters.
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 200
+)
+
+// List is a parsed set of pagination/sort parameters.
+type List struct {
+	Limit       int64
+	HasCursor   bool
+	CursorValue interface{}
+	CursorID    primitive.ObjectID
+	SortColumn  string
+	SortOrder   int
+}
+
+// Parse reads limit, cursor, sort_column and sort_order from c's query
+// string. sortable is the set of field names the caller allows sorting by;
+// defaultSort is used when sort_column is absent.
+func Parse(c *fiber.Ctx, sortable map[string]bool, defaultSort string) (List, error) {
+	lst := List{Limit: defaultLimit, SortColumn: defaultSort, SortOrder: 1}
+
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			return List{}, errors.New("limit must be a positive integer")
+		}
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+		lst.Limit = limit
+	}
+
+	if raw := strings.TrimSpace(c.Query("sort_column")); raw != "" {
+		if !sortable[raw] {
+			return List{}, errors.New("sort_column is not a sortable field")
+		}
+		lst.SortColumn = raw
+	}
+
+	if raw := strings.TrimSpace(c.Query("sort_order")); raw != "" {
+		switch strings.ToLower(raw) {
+		case "asc":
+			lst.SortOrder = 1
+		case "desc":
+			lst.SortOrder = -1
+		default:
+			return List{}, errors.New("sort_order must be asc or desc")
+		}
+	}
+
+	if raw := strings.TrimSpace(c.Query("cursor")); raw != "" {
+		value, id, err := DecodeCursor(raw)
+		if err != nil {
+			return List{}, errors.New("cursor is malformed")
+		}
+		lst.CursorValue = value
+		lst.CursorID = id
+		lst.HasCursor = true
+	}
+
+	return lst, nil
+}
+
+// Apply merges the cursor into filter and builds the matching FindOptions,
+// sorting by SortColumn with _id as a stable tiebreaker. The cursor is a
+// compound (SortColumn value, _id) pair: since results are sorted by
+// SortColumn first, a boundary keyed only on _id would skip or repeat
+// documents whenever insertion order doesn't match the sort order, so the
+// filter instead keeps everything strictly after the cursor in sort order -
+// SortColumn past the cursor's value, or equal to it with a greater _id. It
+// requests one extra document over Limit so callers can tell whether a next
+// page exists.
+func (l List) Apply(filter bson.M) (bson.M, *options.FindOptions) {
+	merged := bson.M{}
+	for k, v := range filter {
+		merged[k] = v
+	}
+
+	if l.HasCursor {
+		cmp := "$gt"
+		if l.SortOrder < 0 {
+			cmp = "$lt"
+		}
+		merged["$or"] = []bson.M{
+			{l.SortColumn: bson.M{cmp: l.CursorValue}},
+			{l.SortColumn: l.CursorValue, "_id": bson.M{cmp: l.CursorID}},
+		}
+	}
+
+	opts := options.Find().
+		SetLimit(l.Limit + 1).
+		SetSort(bson.D{
+			{Key: l.SortColumn, Value: l.SortOrder},
+			{Key: "_id", Value: l.SortOrder},
+		})
+
+	return merged, opts
+}
+
+// SortValue reads field off doc via reflection, for encoding the cursor of
+// the last document on a page. doc is the plain model struct (not the *Doc
+// wrapper), and field is the List's SortColumn.
+func SortValue(doc interface{}, field string) interface{} {
+	v := reflect.ValueOf(doc).FieldByName(field)
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// cursorPayload is the JSON shape encoded into the opaque cursor token.
+type cursorPayload struct {
+	V  interface{} `json:"v"`
+	ID string      `json:"id"`
+}
+
+// EncodeCursor turns the last document's sort-column value and its _id into
+// the opaque cursor token clients pass back in ?cursor=.
+func EncodeCursor(sortValue interface{}, id primitive.ObjectID) string {
+	data, err := json.Marshal(cursorPayload{V: sortValue, ID: id.Hex()})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(token string) (interface{}, primitive.ObjectID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(payload.ID)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	return payload.V, id, nil
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEncodeDecodeCursorRoundTrip(t *testing.T) {
+	cases := []interface{}{"Widgets", float64(42), ""}
+
+	for _, value := range cases {
+		id := primitive.NewObjectID()
+
+		token := EncodeCursor(value, id)
+		if token == "" {
+			t.Fatalf("EncodeCursor(%v, %v) returned an empty token", value, id)
+		}
+
+		gotValue, gotID, err := DecodeCursor(token)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", token, err)
+		}
+		if gotValue != value {
+			t.Errorf("DecodeCursor value = %v, want %v", gotValue, value)
+		}
+		if gotID != id {
+			t.Errorf("DecodeCursor id = %v, want %v", gotID, id)
+		}
+	}
+}
+
+func TestDecodeCursorMalformed(t *testing.T) {
+	if _, _, err := DecodeCursor("not-valid-base64!!"); err == nil {
+		t.Fatal("DecodeCursor accepted a malformed token")
+	}
+}
+
+func TestApplyCompoundCursorAscending(t *testing.T) {
+	id := primitive.NewObjectID()
+	l := List{
+		Limit:       20,
+		HasCursor:   true,
+		CursorValue: "Midway",
+		CursorID:    id,
+		SortColumn:  "ProductName",
+		SortOrder:   1,
+	}
+
+	filter, opts := l.Apply(bson.M{"StockID": "stock-1"})
+
+	or, ok := filter["$or"].([]bson.M)
+	if !ok || len(or) != 2 {
+		t.Fatalf("filter[$or] = %#v, want a 2-element []bson.M", filter["$or"])
+	}
+
+	gt, ok := or[0]["ProductName"].(bson.M)
+	if !ok || gt["$gt"] != "Midway" {
+		t.Errorf("or[0] = %#v, want ProductName $gt Midway", or[0])
+	}
+
+	tie, ok := or[1]["_id"].(bson.M)
+	if !ok || tie["$gt"] != id || or[1]["ProductName"] != "Midway" {
+		t.Errorf("or[1] = %#v, want ProductName == Midway and _id $gt %v", or[1], id)
+	}
+
+	if filter["StockID"] != "stock-1" {
+		t.Errorf("filter lost the caller's own StockID clause: %#v", filter)
+	}
+	if opts.Limit == nil || *opts.Limit != 21 {
+		t.Errorf("opts.Limit = %v, want 21 (Limit+1)", opts.Limit)
+	}
+}
+
+func TestApplyCompoundCursorDescendingFlipsComparison(t *testing.T) {
+	id := primitive.NewObjectID()
+	l := List{
+		Limit:       20,
+		HasCursor:   true,
+		CursorValue: "Midway",
+		CursorID:    id,
+		SortColumn:  "ProductName",
+		SortOrder:   -1,
+	}
+
+	filter, _ := l.Apply(bson.M{})
+
+	or := filter["$or"].([]bson.M)
+	if _, ok := or[0]["ProductName"].(bson.M)["$lt"]; !ok {
+		t.Errorf("descending sort should compare with $lt, got %#v", or[0])
+	}
+	if _, ok := or[1]["_id"].(bson.M)["$lt"]; !ok {
+		t.Errorf("descending sort should tiebreak with $lt, got %#v", or[1])
+	}
+}
+
+func TestApplyWithoutCursorLeavesFilterUntouched(t *testing.T) {
+	l := List{Limit: 5, SortColumn: "StockName", SortOrder: 1}
+
+	filter, _ := l.Apply(bson.M{"UserID": "u-1"})
+
+	if _, ok := filter["$or"]; ok {
+		t.Errorf("filter should have no $or clause without a cursor: %#v", filter)
+	}
+	if filter["UserID"] != "u-1" {
+		t.Errorf("filter lost the caller's own UserID clause: %#v", filter)
+	}
+}
+
+func TestSortValue(t *testing.T) {
+	type doc struct {
+		StockName string
+	}
+
+	if got := SortValue(doc{StockName: "Bolts"}, "StockName"); got != "Bolts" {
+		t.Errorf("SortValue = %v, want Bolts", got)
+	}
+	if got := SortValue(doc{StockName: "Bolts"}, "Missing"); got != nil {
+		t.Errorf("SortValue of a missing field = %v, want nil", got)
+	}
+}
@@ -0,0 +1,94 @@
+package realtime
+
+import (
+	"context"
+	"log"
+
+	"my-backend/internal/db"
+	"my-backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WatchCollection tails coll's MongoDB change stream and republishes writes
+// onto the Hub under object, so edits made outside our own handlers (direct
+// Mongo access, another service, mongosh) still reach subscribed clients.
+// Product and category documents carry a StockID but no UserID of their
+// own, so their owning stock is looked up to route the event by UserID too.
+// It blocks until ctx is cancelled or the stream errors.
+func (h *Hub) WatchCollection(ctx context.Context, coll *mongo.Collection, object string) {
+	stream, err := coll.Watch(ctx, mongo.Pipeline{}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		log.Printf("realtime: watch %s: %v", object, err)
+		return
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var changed struct {
+			OperationType string `bson:"operationType"`
+			FullDocument  bson.M `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&changed); err != nil {
+			continue
+		}
+		if changed.FullDocument == nil {
+			// Deletes carry no fullDocument; there is no UserID/StockID left
+			// to route on, so these rely on the handler-side Publish call.
+			continue
+		}
+
+		stockID := extractUUID(changed.FullDocument, "StockID")
+		userID := extractUUID(changed.FullDocument, "UserID")
+		if userID == uuid.Nil && stockID != uuid.Nil {
+			if warehouseCol, err := db.WarehouseCollection(ctx); err == nil {
+				userID = stockOwner(ctx, warehouseCol, stockID)
+			}
+		}
+
+		h.Publish(Event{
+			Object:  object,
+			Action:  changed.OperationType,
+			Data:    changed.FullDocument,
+			UserID:  userID,
+			StockID: stockID,
+		})
+	}
+}
+
+// stockOwner looks up the UserID that owns stockID against warehouseCol, for
+// routing product and category change-stream events, which carry a StockID
+// but no UserID of their own. Returns uuid.Nil if the stock can't be found.
+// Taking the collection as a parameter keeps this testable against a mocked
+// Mongo deployment, the way seeds.importInto is.
+func stockOwner(ctx context.Context, warehouseCol *mongo.Collection, stockID uuid.UUID) uuid.UUID {
+	var stock models.Warehouse
+	if err := warehouseCol.FindOne(ctx, bson.M{"StockID": stockID}).Decode(&stock); err != nil {
+		return uuid.Nil
+	}
+	return stock.UserID
+}
+
+// extractUUID reads key from doc as a uuid.UUID, returning uuid.Nil if it is
+// absent or not a well-formed UUID.
+func extractUUID(doc bson.M, key string) uuid.UUID {
+	raw, ok := doc[key]
+	if !ok {
+		return uuid.Nil
+	}
+
+	data, err := bson.Marshal(bson.M{"v": raw})
+	if err != nil {
+		return uuid.Nil
+	}
+	var decoded struct {
+		V uuid.UUID `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &decoded); err != nil {
+		return uuid.Nil
+	}
+	return decoded.V
+}
@@ -0,0 +1,50 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestStockOwnerReturnsMatchingUserID covers the happy path: a product or
+// category change-stream event carries only a StockID, and stockOwner looks
+// up the stock document to find who actually owns it.
+func TestStockOwnerReturnsMatchingUserID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("owner found", func(mt *mtest.T) {
+		stockID, userID := uuid.New(), uuid.New()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.warehouse", mtest.FirstBatch, bson.D{
+			{Key: "StockID", Value: stockID},
+			{Key: "UserID", Value: userID},
+			{Key: "StockName", Value: "Main"},
+		}))
+
+		got := stockOwner(context.Background(), mt.Coll, stockID)
+		if got != userID {
+			t.Errorf("stockOwner = %v, want %v", got, userID)
+		}
+	})
+}
+
+// TestStockOwnerReturnsNilWhenStockMissing covers the case a product or
+// category event references a StockID that no longer resolves to a stock;
+// stockOwner should report uuid.Nil rather than erroring.
+func TestStockOwnerReturnsNilWhenStockMissing(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("owner not found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.warehouse", mtest.FirstBatch))
+
+		got := stockOwner(context.Background(), mt.Coll, uuid.New())
+		if got != uuid.Nil {
+			t.Errorf("stockOwner = %v, want uuid.Nil", got)
+		}
+	})
+}
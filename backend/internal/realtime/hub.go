@@ -0,0 +1,101 @@
+// Package realtime fans out create/update/delete notifications for
+// warehouse/product/category mutations to WebSocket clients, so multiple
+// tabs and devices watching the same stock stay in sync.
+package realtime
+
+import (
+	"sync"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+)
+
+// Event is a single change notification broadcast to subscribed clients.
+type Event struct {
+	Object        string      `json:"object"`
+	Action        string      `json:"action"`
+	Data          interface{} `json:"data"`
+	RequestSource string      `json:"X-Request-Source,omitempty"`
+	UserID        uuid.UUID   `json:"-"`
+	StockID       uuid.UUID   `json:"-"`
+}
+
+type client struct {
+	conn    *websocket.Conn
+	userID  uuid.UUID
+	stockID uuid.UUID
+	send    chan Event
+}
+
+// Hub keeps track of connected WebSocket clients and fans events out to the
+// ones subscribed to a given user or stock topic.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+}
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*client]struct{})}
+}
+
+var defaultHub = NewHub()
+
+// Default returns the process-wide Hub used by handlers and change stream
+// watchers.
+func Default() *Hub {
+	return defaultHub
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// Publish fans evt out to every client whose subscription matches it. A
+// client's userID and stockID are each a filter: when set, evt must carry
+// the same value to be delivered, so a client scoped to both a user and a
+// stock only receives events matching both; a filter left as uuid.Nil
+// matches any value.
+func (h *Hub) Publish(evt Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		if c.userID != uuid.Nil && c.userID != evt.UserID {
+			continue
+		}
+		if c.stockID != uuid.Nil && c.stockID != evt.StockID {
+			continue
+		}
+		select {
+		case c.send <- evt:
+		default:
+			// Slow consumer; drop rather than block the publisher.
+		}
+	}
+}
+
+// Publish broadcasts a mutation made through one of our own handlers on the
+// default Hub. source is the client-supplied X-Request-Source header, echoed
+// back so the originating tab can suppress its own echo.
+func Publish(object, action string, data interface{}, userID, stockID uuid.UUID, source string) {
+	Default().Publish(Event{
+		Object:        object,
+		Action:        action,
+		Data:          data,
+		RequestSource: source,
+		UserID:        userID,
+		StockID:       stockID,
+	})
+}
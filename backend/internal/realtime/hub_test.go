@@ -0,0 +1,75 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// recvOrTimeout drains c.send without blocking the test suite if Publish
+// didn't deliver anything.
+func recvOrTimeout(t *testing.T, c *client) (Event, bool) {
+	t.Helper()
+	select {
+	case evt, ok := <-c.send:
+		return evt, ok
+	default:
+		return Event{}, false
+	}
+}
+
+func TestHubPublishFiltersByUserID(t *testing.T) {
+	h := NewHub()
+	userA, userB := uuid.New(), uuid.New()
+
+	subscribed := &client{userID: userA, send: make(chan Event, 1)}
+	other := &client{userID: userB, send: make(chan Event, 1)}
+	everyone := &client{send: make(chan Event, 1)}
+
+	h.register(subscribed)
+	h.register(other)
+	h.register(everyone)
+
+	h.Publish(Event{Object: "stock", UserID: userA})
+
+	if _, ok := recvOrTimeout(t, subscribed); !ok {
+		t.Error("client subscribed to userA did not receive userA's event")
+	}
+	if _, ok := recvOrTimeout(t, other); ok {
+		t.Error("client subscribed to userB received userA's event")
+	}
+	if _, ok := recvOrTimeout(t, everyone); !ok {
+		t.Error("client with no userId filter did not receive the event")
+	}
+}
+
+func TestHubPublishFiltersByStockID(t *testing.T) {
+	h := NewHub()
+	stockA, stockB := uuid.New(), uuid.New()
+
+	subscribed := &client{stockID: stockA, send: make(chan Event, 1)}
+	other := &client{stockID: stockB, send: make(chan Event, 1)}
+
+	h.register(subscribed)
+	h.register(other)
+
+	h.Publish(Event{Object: "product", StockID: stockA})
+
+	if _, ok := recvOrTimeout(t, subscribed); !ok {
+		t.Error("client subscribed to stockA did not receive stockA's event")
+	}
+	if _, ok := recvOrTimeout(t, other); ok {
+		t.Error("client subscribed to stockB received stockA's event")
+	}
+}
+
+func TestHubUnregisterClosesSendChannel(t *testing.T) {
+	h := NewHub()
+	c := &client{send: make(chan Event, 1)}
+	h.register(c)
+	h.unregister(c)
+
+	if _, ok := <-c.send; ok {
+		t.Error("send channel should be closed after unregister")
+	}
+}
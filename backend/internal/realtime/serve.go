@@ -0,0 +1,40 @@
+package realtime
+
+import (
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+)
+
+// Serve upgrades conn onto the Hub and pumps events to it until the client
+// disconnects. userID is the caller's own UUID, verified from their JWT by
+// the /api/ws upgrade check - callers cannot subscribe to another user's
+// topic. An optional stockId query param further scopes the subscription to
+// a single stock; omitting it subscribes to every stock the user owns.
+func (h *Hub) Serve(conn *websocket.Conn, userID uuid.UUID) {
+	stockID, _ := uuid.Parse(conn.Query("stockId"))
+
+	c := &client{conn: conn, userID: userID, stockID: stockID, send: make(chan Event, 16)}
+	h.register(c)
+	defer h.unregister(c)
+
+	go h.writePump(c)
+	h.readPump(c)
+}
+
+// readPump drains and discards client frames, existing purely to detect
+// disconnects; clients only ever receive on this connection.
+func (h *Hub) readPump(c *client) {
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *Hub) writePump(c *client) {
+	for evt := range c.send {
+		if err := c.conn.WriteJSON(evt); err != nil {
+			return
+		}
+	}
+}
@@ -1,18 +1,57 @@
 package routes
 
 import (
+	"context"
+
+	"my-backend/internal/db"
 	"my-backend/internal/handlers"
+	"my-backend/internal/middleware"
+	"my-backend/internal/realtime"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
 )
 
 func RegisterRoutes(app *fiber.App) {
 	app.Get("/api/health", handlers.HealthCheck)
 
+	// Real-time change feed: clients subscribe to their own user topic, plus
+	// an optional stockId, and receive the same events the CRUD handlers
+	// below publish. Browsers can't set an Authorization header on a
+	// WebSocket handshake, so the JWT travels as ?token= instead and is
+	// verified here before the upgrade is allowed.
+	app.Use("/api/ws", func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+		userID, err := middleware.ParseToken(c.Query("token"))
+		if err != nil {
+			return err
+		}
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/api/ws", websocket.New(func(conn *websocket.Conn) {
+		userID, _ := conn.Locals("userID").(uuid.UUID)
+		realtime.Default().Serve(conn, userID)
+	}))
+
+	// Fallback source for the same change feed: tail each collection so
+	// writes made outside our own handlers (direct Mongo access, another
+	// service, mongosh) still reach subscribed clients.
+	watchChangeStreams()
+
 	//Auth
 	app.Post("/api/register", handlers.RegisterUser)
 	app.Post("/api/login", handlers.LoginUser)
 
+	// Every product/category/warehouse route requires a Bearer JWT; handlers
+	// read the authenticated user from c.Locals("userID") via middleware.UserID.
+	app.Use("/api/products", middleware.Auth)
+	app.Use("/api/categories", middleware.Auth)
+	app.Use("/api/warehouse", middleware.Auth)
+
 	app.Get("/api/products", handlers.ListProducts)
 	app.Delete("/api/products/:productId", handlers.DeleteProduct)
 	app.Put("/api/products/:productId", handlers.UpdateProduct)
@@ -24,5 +63,24 @@ func RegisterRoutes(app *fiber.App) {
 	app.Get("/api/warehouse", handlers.ListWarehouse)
 	app.Post("/api/warehouse", handlers.CreateStock)
 	app.Delete("/api/warehouse/:stockId", handlers.DeleteStock)
+	app.Post("/api/warehouse/import", handlers.ImportWarehouse)
+	app.Get("/api/warehouse/export", handlers.ExportWarehouse)
 	app.Delete("/api/categories/:categoryId", handlers.DeleteCategory)
 }
+
+// watchChangeStreams starts one Hub.WatchCollection goroutine per collection
+// backing the realtime feed. It runs for the lifetime of the process, so it
+// uses context.Background() rather than a request-scoped context.
+func watchChangeStreams() {
+	ctx := context.Background()
+
+	if coll, err := db.WarehouseCollection(ctx); err == nil {
+		go realtime.Default().WatchCollection(ctx, coll, "stock")
+	}
+	if coll, err := db.ProductsCollection(ctx); err == nil {
+		go realtime.Default().WatchCollection(ctx, coll, "product")
+	}
+	if coll, err := db.CategoriesCollection(ctx); err == nil {
+		go realtime.Default().WatchCollection(ctx, coll, "category")
+	}
+}
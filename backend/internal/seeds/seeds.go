@@ -0,0 +1,321 @@
+// Package seeds bulk-imports and exports a user's entire warehouse as a
+// single nested JSON document, for bootstrapping a fresh environment or
+// backing up and restoring an existing one.
+package seeds
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"my-backend/internal/db"
+	"my-backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProductSeed is one product within an imported/exported category.
+type ProductSeed struct {
+	ProductName string `json:"ProductName"`
+	ProductQty  int    `json:"ProductQty"`
+	Unit        string `json:"Unit"`
+}
+
+// CategorySeed is one category within an imported/exported stock.
+type CategorySeed struct {
+	CategoryName string        `json:"CategoryName"`
+	Discription  string        `json:"Discription,omitempty"`
+	Products     []ProductSeed `json:"products"`
+}
+
+// StockSeed is one stock within an imported/exported warehouse.
+type StockSeed struct {
+	StockName  string         `json:"StockName"`
+	Categories []CategorySeed `json:"categories"`
+}
+
+// Warehouse is the top-level nested document accepted by the import
+// endpoint and produced by the export endpoint.
+type Warehouse struct {
+	Stocks []StockSeed `json:"stocks"`
+}
+
+// Result reports how many new documents an Import created; existing
+// stocks/categories matched by name under ?upsert=true are not counted.
+type Result struct {
+	CreatedStocks     int `json:"created_stocks"`
+	CreatedCategories int `json:"created_categories"`
+	CreatedProducts   int `json:"created_products"`
+}
+
+// Import creates stocks, categories and products for userID from doc,
+// linking them with generated UUIDs via ordered InsertMany calls (warehouse,
+// then categories, then products). When upsert is true, a stock or category
+// already matching by name is reused instead of duplicated, and a product
+// already matching by name within its category is left alone rather than
+// inserted again.
+func Import(ctx context.Context, userID uuid.UUID, doc Warehouse, upsert bool) (Result, error) {
+	warehouseCol, err := db.WarehouseCollection(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	categoriesCol, err := db.CategoriesCollection(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	productsCol, err := db.ProductsCollection(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return importInto(ctx, userID, doc, upsert, warehouseCol, categoriesCol, productsCol)
+}
+
+// importInto holds Import's actual logic against already-resolved
+// collections, so it can be unit tested against a mocked Mongo deployment
+// without going through the db package.
+func importInto(ctx context.Context, userID uuid.UUID, doc Warehouse, upsert bool, warehouseCol, categoriesCol, productsCol *mongo.Collection) (Result, error) {
+	var result Result
+
+	var newStocks, newCategories, newProducts []interface{}
+
+	// pendingStocks tracks stock names created earlier in this same call, so a
+	// duplicate StockName within one payload reuses that stock instead of
+	// insert-racing past findStockID's database-only check.
+	pendingStocks := make(map[string]uuid.UUID)
+
+	// pendingCategories does the same for categories, keyed by the stock they
+	// belong to plus their CategoryName, since a duplicate CategoryName under
+	// a stock created earlier in this same payload hasn't reached the
+	// database yet for findCategoryID to match against.
+	type categoryKey struct {
+		stockID uuid.UUID
+		name    string
+	}
+	pendingCategories := make(map[categoryKey]uuid.UUID)
+
+	for _, stockSeed := range doc.Stocks {
+		stockID := uuid.Nil
+		if upsert {
+			stockID = pendingStocks[stockSeed.StockName]
+			if stockID == uuid.Nil {
+				var err error
+				stockID, err = findStockID(ctx, warehouseCol, userID, stockSeed.StockName)
+				if err != nil {
+					return result, err
+				}
+			}
+		}
+		if stockID == uuid.Nil {
+			stockID = uuid.New()
+			newStocks = append(newStocks, models.Warehouse{
+				StockID:   stockID,
+				UserID:    userID,
+				StockName: stockSeed.StockName,
+			})
+			result.CreatedStocks++
+		}
+		if upsert {
+			pendingStocks[stockSeed.StockName] = stockID
+		}
+
+		for _, categorySeed := range stockSeed.Categories {
+			catKey := categoryKey{stockID: stockID, name: categorySeed.CategoryName}
+			categoryID := uuid.Nil
+			if upsert {
+				categoryID = pendingCategories[catKey]
+				if categoryID == uuid.Nil {
+					var err error
+					categoryID, err = findCategoryID(ctx, categoriesCol, stockID, categorySeed.CategoryName)
+					if err != nil {
+						return result, err
+					}
+				}
+			}
+			if categoryID == uuid.Nil {
+				categoryID = uuid.New()
+				newCategories = append(newCategories, models.Categories{
+					CategoryID:   categoryID,
+					StockID:      stockID,
+					CategoryName: categorySeed.CategoryName,
+					Discription:  categorySeed.Discription,
+				})
+				result.CreatedCategories++
+			}
+			if upsert {
+				pendingCategories[catKey] = categoryID
+			}
+
+			for _, productSeed := range categorySeed.Products {
+				if upsert {
+					exists, err := productExists(ctx, productsCol, stockID, categorySeed.CategoryName, productSeed.ProductName)
+					if err != nil {
+						return result, err
+					}
+					if exists {
+						continue
+					}
+				}
+				newProducts = append(newProducts, models.Products{
+					ProductID:   uuid.New(),
+					StockID:     stockID,
+					ProductName: productSeed.ProductName,
+					Category:    categorySeed.CategoryName,
+					Unit:        productSeed.Unit,
+					ProductQty:  productSeed.ProductQty,
+				})
+				result.CreatedProducts++
+			}
+		}
+	}
+
+	if len(newStocks) > 0 {
+		if _, err := warehouseCol.InsertMany(ctx, newStocks); err != nil {
+			return result, err
+		}
+	}
+	if len(newCategories) > 0 {
+		if _, err := categoriesCol.InsertMany(ctx, newCategories); err != nil {
+			return result, err
+		}
+	}
+	if len(newProducts) > 0 {
+		if _, err := productsCol.InsertMany(ctx, newProducts); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Export reads userID's entire warehouse back into the same nested shape
+// Import accepts.
+func Export(ctx context.Context, userID uuid.UUID) (Warehouse, error) {
+	var doc Warehouse
+
+	warehouseCol, err := db.WarehouseCollection(ctx)
+	if err != nil {
+		return doc, err
+	}
+	categoriesCol, err := db.CategoriesCollection(ctx)
+	if err != nil {
+		return doc, err
+	}
+	productsCol, err := db.ProductsCollection(ctx)
+	if err != nil {
+		return doc, err
+	}
+
+	stockCursor, err := warehouseCol.Find(ctx, bson.M{"UserID": userID})
+	if err != nil {
+		return doc, err
+	}
+	defer stockCursor.Close(ctx)
+
+	var stocks []models.Warehouse
+	if err := stockCursor.All(ctx, &stocks); err != nil {
+		return doc, err
+	}
+
+	for _, stock := range stocks {
+		categoryCursor, err := categoriesCol.Find(ctx, bson.M{"StockID": stock.StockID})
+		if err != nil {
+			return doc, err
+		}
+		var categories []models.Categories
+		if err := categoryCursor.All(ctx, &categories); err != nil {
+			categoryCursor.Close(ctx)
+			return doc, err
+		}
+		categoryCursor.Close(ctx)
+
+		// Fetch every product for the stock once and group by its free-text
+		// Category field, rather than querying per persisted category name:
+		// a product whose Category was left blank, typoed, or renamed/deleted
+		// since the product was created would otherwise match no category
+		// and be silently dropped from the export.
+		productCursor, err := productsCol.Find(ctx, bson.M{"StockID": stock.StockID})
+		if err != nil {
+			return doc, err
+		}
+		var products []models.Products
+		if err := productCursor.All(ctx, &products); err != nil {
+			productCursor.Close(ctx)
+			return doc, err
+		}
+		productCursor.Close(ctx)
+
+		productsByCategory := make(map[string][]ProductSeed)
+		for _, product := range products {
+			productsByCategory[product.Category] = append(productsByCategory[product.Category], ProductSeed{
+				ProductName: product.ProductName,
+				ProductQty:  product.ProductQty,
+				Unit:        product.Unit,
+			})
+		}
+
+		var stockSeed StockSeed
+		stockSeed.StockName = stock.StockName
+		for _, category := range categories {
+			stockSeed.Categories = append(stockSeed.Categories, CategorySeed{
+				CategoryName: category.CategoryName,
+				Discription:  category.Discription,
+				Products:     productsByCategory[category.CategoryName],
+			})
+			delete(productsByCategory, category.CategoryName)
+		}
+
+		// Anything left in productsByCategory belongs to a category name that
+		// no longer has a matching Categories document; emit it as its own
+		// seed entry so the products still round-trip through import.
+		leftoverNames := make([]string, 0, len(productsByCategory))
+		for name := range productsByCategory {
+			leftoverNames = append(leftoverNames, name)
+		}
+		sort.Strings(leftoverNames)
+		for _, name := range leftoverNames {
+			stockSeed.Categories = append(stockSeed.Categories, CategorySeed{
+				CategoryName: name,
+				Products:     productsByCategory[name],
+			})
+		}
+
+		doc.Stocks = append(doc.Stocks, stockSeed)
+	}
+
+	return doc, nil
+}
+
+func findStockID(ctx context.Context, col *mongo.Collection, userID uuid.UUID, name string) (uuid.UUID, error) {
+	var existing models.Warehouse
+	err := col.FindOne(ctx, bson.M{"UserID": userID, "StockName": name}).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return existing.StockID, nil
+}
+
+func findCategoryID(ctx context.Context, col *mongo.Collection, stockID uuid.UUID, name string) (uuid.UUID, error) {
+	var existing models.Categories
+	err := col.FindOne(ctx, bson.M{"StockID": stockID, "CategoryName": name}).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return existing.CategoryID, nil
+}
+
+func productExists(ctx context.Context, col *mongo.Collection, stockID uuid.UUID, category, productName string) (bool, error) {
+	count, err := col.CountDocuments(ctx, bson.M{"StockID": stockID, "Category": category, "ProductName": productName})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
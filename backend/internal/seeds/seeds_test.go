@@ -0,0 +1,222 @@
+package seeds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func sampleDoc() Warehouse {
+	return Warehouse{
+		Stocks: []StockSeed{{
+			StockName: "Main",
+			Categories: []CategorySeed{{
+				CategoryName: "Bolts",
+				Products: []ProductSeed{{
+					ProductName: "M4 Bolt",
+					ProductQty:  100,
+					Unit:        "box",
+				}},
+			}},
+		}},
+	}
+}
+
+// TestImportIntoCreatesWhenNotUpserting covers the create path: with
+// upsert=false, Import never looks for an existing match and always inserts
+// a brand new stock, category and product.
+func TestImportIntoCreatesWhenNotUpserting(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("create path", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		result, err := importInto(context.Background(), uuid.New(), sampleDoc(), false, mt.Coll, mt.Coll, mt.Coll)
+		if err != nil {
+			t.Fatalf("importInto: %v", err)
+		}
+
+		if result.CreatedStocks != 1 || result.CreatedCategories != 1 || result.CreatedProducts != 1 {
+			t.Errorf("result = %#v, want one of each created", result)
+		}
+	})
+}
+
+// TestImportIntoReusesMatchesUnderUpsert covers the upsert-match path: when
+// a stock, category and product already exist by name, Import reuses them
+// instead of inserting duplicates.
+func TestImportIntoReusesMatchesUnderUpsert(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("upsert match path", func(mt *mtest.T) {
+		stockID := uuid.New()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.warehouse", mtest.FirstBatch, bson.D{
+			{Key: "StockID", Value: stockID},
+			{Key: "UserID", Value: uuid.New()},
+			{Key: "StockName", Value: "Main"},
+		}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.categories", mtest.FirstBatch, bson.D{
+			{Key: "CategoryID", Value: uuid.New()},
+			{Key: "StockID", Value: stockID},
+			{Key: "CategoryName", Value: "Bolts"},
+		}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: 1},
+		}))
+
+		result, err := importInto(context.Background(), uuid.New(), sampleDoc(), true, mt.Coll, mt.Coll, mt.Coll)
+		if err != nil {
+			t.Fatalf("importInto: %v", err)
+		}
+
+		if result.CreatedStocks != 0 || result.CreatedCategories != 0 || result.CreatedProducts != 0 {
+			t.Errorf("result = %#v, want nothing created when a stock/category/product already match by name", result)
+		}
+	})
+}
+
+// TestImportIntoCreatesMissingChildUnderUpsert covers the mixed case: an
+// existing stock and category are reused, but a product that doesn't match
+// by name within that category is still created.
+func TestImportIntoCreatesMissingChildUnderUpsert(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("upsert create-child path", func(mt *mtest.T) {
+		stockID := uuid.New()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.warehouse", mtest.FirstBatch, bson.D{
+			{Key: "StockID", Value: stockID},
+			{Key: "UserID", Value: uuid.New()},
+			{Key: "StockName", Value: "Main"},
+		}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.categories", mtest.FirstBatch, bson.D{
+			{Key: "CategoryID", Value: uuid.New()},
+			{Key: "StockID", Value: stockID},
+			{Key: "CategoryName", Value: "Bolts"},
+		}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{
+			{Key: "n", Value: 0},
+		}))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		result, err := importInto(context.Background(), uuid.New(), sampleDoc(), true, mt.Coll, mt.Coll, mt.Coll)
+		if err != nil {
+			t.Fatalf("importInto: %v", err)
+		}
+
+		if result.CreatedStocks != 0 || result.CreatedCategories != 0 {
+			t.Errorf("result = %#v, want the matched stock/category reused", result)
+		}
+		if result.CreatedProducts != 1 {
+			t.Errorf("result.CreatedProducts = %d, want 1 for the non-matching product", result.CreatedProducts)
+		}
+	})
+}
+
+// TestImportIntoDedupsNewStockNameWithinPayload covers a payload with two
+// stocks sharing a StockName that doesn't exist yet: under upsert=true, only
+// the first occurrence should create a stock, and the second should reuse it
+// rather than inserting a same-name duplicate.
+func TestImportIntoDedupsNewStockNameWithinPayload(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("duplicate new stock name", func(mt *mtest.T) {
+		doc := Warehouse{
+			Stocks: []StockSeed{
+				{
+					StockName: "Main",
+					Categories: []CategorySeed{{
+						CategoryName: "Bolts",
+						Products: []ProductSeed{{ProductName: "M4 Bolt", ProductQty: 100, Unit: "box"}},
+					}},
+				},
+				{
+					StockName: "Main",
+					Categories: []CategorySeed{{
+						CategoryName: "Nuts",
+						Products: []ProductSeed{{ProductName: "M4 Nut", ProductQty: 50, Unit: "box"}},
+					}},
+				},
+			},
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.warehouse", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.categories", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.categories", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		result, err := importInto(context.Background(), uuid.New(), doc, true, mt.Coll, mt.Coll, mt.Coll)
+		if err != nil {
+			t.Fatalf("importInto: %v", err)
+		}
+
+		if result.CreatedStocks != 1 {
+			t.Errorf("result.CreatedStocks = %d, want 1 for a StockName repeated within one payload", result.CreatedStocks)
+		}
+		if result.CreatedCategories != 2 || result.CreatedProducts != 2 {
+			t.Errorf("result = %#v, want both distinct categories/products still created", result)
+		}
+	})
+}
+
+// TestImportIntoDedupsNewCategoryNameWithinPayload covers a payload with one
+// not-yet-existing stock holding two categories sharing a CategoryName:
+// under upsert=true, only the first occurrence should create a category,
+// and the second should reuse it rather than inserting a same-name
+// duplicate under the same stock.
+func TestImportIntoDedupsNewCategoryNameWithinPayload(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("duplicate new category name", func(mt *mtest.T) {
+		doc := Warehouse{
+			Stocks: []StockSeed{{
+				StockName: "Main",
+				Categories: []CategorySeed{
+					{
+						CategoryName: "Bolts",
+						Products:     []ProductSeed{{ProductName: "M4 Bolt", ProductQty: 100, Unit: "box"}},
+					},
+					{
+						CategoryName: "Bolts",
+						Products:     []ProductSeed{{ProductName: "M5 Bolt", ProductQty: 50, Unit: "box"}},
+					},
+				},
+			}},
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.warehouse", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "my-backend.categories", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}))
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "my-backend.products", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		result, err := importInto(context.Background(), uuid.New(), doc, true, mt.Coll, mt.Coll, mt.Coll)
+		if err != nil {
+			t.Fatalf("importInto: %v", err)
+		}
+
+		if result.CreatedCategories != 1 {
+			t.Errorf("result.CreatedCategories = %d, want 1 for a CategoryName repeated within one stock's payload", result.CreatedCategories)
+		}
+		if result.CreatedProducts != 2 {
+			t.Errorf("result.CreatedProducts = %d, want both distinct products still created", result.CreatedProducts)
+		}
+	})
+}